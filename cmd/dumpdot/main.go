@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"regexp/nfa"
+	"regexp/parser"
+	"regexp/token"
+)
+
+func main() {
+	pattern := "a*|b"
+
+	tokens, err := token.Tokenize(pattern)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	ast, err := parser.Parse(tokens)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	n := nfa.CreateNFA(ast)
+	n.DumpDOT()
+}
@@ -0,0 +1,353 @@
+package dfa
+
+import (
+	"fmt"
+	"regexp/nfa"
+	"regexp/parser"
+	"regexp/token"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type State struct {
+	ID     int
+	Accept bool
+	Nexts  map[rune]*State
+}
+
+// DFA is a deterministic finite automaton built from an NFA by subset
+// construction.
+type DFA struct {
+	States     []*State
+	StartState *State
+}
+
+func newState(id int, accept bool) *State {
+	return &State{
+		ID:     id,
+		Accept: accept,
+		Nexts:  make(map[rune]*State),
+	}
+}
+
+// epsilonClosure returns the set of NFA states reachable from states
+// via zero or more ε-transitions.
+func epsilonClosure(states []*nfa.State) []*nfa.State {
+	visited := make(map[int]*nfa.State)
+	stack := append([]*nfa.State{}, states...)
+
+	for len(stack) > 0 {
+		s := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if _, ok := visited[s.ID]; ok {
+			continue
+		}
+		visited[s.ID] = s
+		stack = append(stack, s.Nexts['ε']...)
+	}
+
+	closure := make([]*nfa.State, 0, len(visited))
+	for _, s := range visited {
+		closure = append(closure, s)
+	}
+	sort.Slice(closure, func(i, j int) bool { return closure[i].ID < closure[j].ID })
+	return closure
+}
+
+// key returns a canonical string for a set of NFA states, used to dedup
+// DFA states produced by subset construction.
+func key(states []*nfa.State) string {
+	ids := make([]int, len(states))
+	for i, s := range states {
+		ids[i] = s.ID
+	}
+	sort.Ints(ids)
+
+	strs := make([]string, len(ids))
+	for i, id := range ids {
+		strs[i] = strconv.Itoa(id)
+	}
+	return strings.Join(strs, ",")
+}
+
+func alphabet(n *nfa.NFA) []rune {
+	set := make(map[rune]bool)
+	for _, s := range n.States {
+		for c := range s.Nexts {
+			if c == 'ε' {
+				continue
+			}
+			set[c] = true
+		}
+	}
+
+	runes := make([]rune, 0, len(set))
+	for c := range set {
+		runes = append(runes, c)
+	}
+	sort.Slice(runes, func(i, j int) bool { return runes[i] < runes[j] })
+	return runes
+}
+
+func isAccept(states []*nfa.State, accepts []*nfa.State) bool {
+	acceptIDs := make(map[int]bool, len(accepts))
+	for _, a := range accepts {
+		acceptIDs[a.ID] = true
+	}
+	for _, s := range states {
+		if acceptIDs[s.ID] {
+			return true
+		}
+	}
+	return false
+}
+
+// FromNFA converts an ε-NFA into a DFA via subset construction. States
+// of the DFA are ε-closed sets of NFA states; a DFA state is accepting
+// iff its set intersects n.AcceptStates.
+func FromNFA(n *nfa.NFA) *DFA {
+	alpha := alphabet(n)
+	dfaStates := make(map[string]*State)
+	stateCount := 0
+
+	startSet := epsilonClosure([]*nfa.State{n.StartState})
+	startKey := key(startSet)
+	start := newState(stateCount, isAccept(startSet, n.AcceptStates))
+	stateCount++
+	dfaStates[startKey] = start
+
+	type unprocessed struct {
+		set   []*nfa.State
+		state *State
+	}
+	queue := []unprocessed{{startSet, start}}
+	allStates := []*State{start}
+
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+
+		for _, c := range alpha {
+			nexts := []*nfa.State{}
+			for _, s := range cur.set {
+				nexts = append(nexts, s.Nexts[c]...)
+			}
+			if len(nexts) == 0 {
+				continue
+			}
+
+			closure := epsilonClosure(nexts)
+			k := key(closure)
+			dst, ok := dfaStates[k]
+			if !ok {
+				dst = newState(stateCount, isAccept(closure, n.AcceptStates))
+				stateCount++
+				dfaStates[k] = dst
+				allStates = append(allStates, dst)
+				queue = append(queue, unprocessed{closure, dst})
+			}
+			cur.state.Nexts[c] = dst
+		}
+	}
+
+	return &DFA{States: allStates, StartState: start}
+}
+
+func dfaAlphabet(d *DFA) []rune {
+	set := make(map[rune]bool)
+	for _, s := range d.States {
+		for c := range s.Nexts {
+			set[c] = true
+		}
+	}
+
+	runes := make([]rune, 0, len(set))
+	for c := range set {
+		runes = append(runes, c)
+	}
+	sort.Slice(runes, func(i, j int) bool { return runes[i] < runes[j] })
+	return runes
+}
+
+// Minimize collapses equivalent states by Moore's algorithm: start
+// from the coarsest partition {accept, non-accept} and repeatedly
+// recompute each state's (block, c-transition block for every c in
+// the alphabet) signature, splitting blocks whose members disagree,
+// until a round produces no new blocks.
+func Minimize(d *DFA) *DFA {
+	alpha := dfaAlphabet(d)
+
+	blockOf := make(map[int]int)
+	hasBlock := [2]bool{}
+	for _, s := range d.States {
+		if s.Accept {
+			blockOf[s.ID] = 1
+		} else {
+			blockOf[s.ID] = 0
+		}
+		hasBlock[blockOf[s.ID]] = true
+	}
+	// Either initial block may be empty (e.g. every state accepts).
+	numBlocks := 0
+	for _, ok := range hasBlock {
+		if ok {
+			numBlocks++
+		}
+	}
+
+	for {
+		signatures := make(map[string]int)
+		newBlockOf := make(map[int]int)
+		next := 0
+
+		for _, s := range d.States {
+			var sig strings.Builder
+			sig.WriteString(strconv.Itoa(blockOf[s.ID]))
+			for _, c := range alpha {
+				sig.WriteByte(',')
+				if dst, ok := s.Nexts[c]; ok {
+					sig.WriteString(strconv.Itoa(blockOf[dst.ID]))
+				} else {
+					sig.WriteByte('-')
+				}
+			}
+
+			id, ok := signatures[sig.String()]
+			if !ok {
+				id = next
+				signatures[sig.String()] = id
+				next++
+			}
+			newBlockOf[s.ID] = id
+		}
+
+		blockOf = newBlockOf
+		if next == numBlocks {
+			break
+		}
+		numBlocks = next
+	}
+
+	blockStates := make(map[int]*State, numBlocks)
+	for _, s := range d.States {
+		b := blockOf[s.ID]
+		if _, ok := blockStates[b]; !ok {
+			blockStates[b] = newState(b, s.Accept)
+		}
+	}
+
+	for _, s := range d.States {
+		cur := blockStates[blockOf[s.ID]]
+		for c, dst := range s.Nexts {
+			cur.Nexts[c] = blockStates[blockOf[dst.ID]]
+		}
+	}
+
+	minStates := make([]*State, 0, len(blockStates))
+	for _, s := range blockStates {
+		minStates = append(minStates, s)
+	}
+	sort.Slice(minStates, func(i, j int) bool { return minStates[i].ID < minStates[j].ID })
+
+	return &DFA{States: minStates, StartState: blockStates[blockOf[d.StartState.ID]]}
+}
+
+// containsAnchor reports whether ast has a '^' or '$' anywhere in it.
+// ND_BEGIN/ND_END compile to edges on nfa's unexported sentinel runes,
+// which subset construction has no way to tell apart from ordinary
+// symbols, so Compile must refuse them up front instead of silently
+// building a DFA that can never accept.
+func containsAnchor(ast *parser.Node) bool {
+	if ast == nil {
+		return false
+	}
+	if ast.Type == parser.ND_BEGIN || ast.Type == parser.ND_END {
+		return true
+	}
+	return containsAnchor(ast.Lhs) || containsAnchor(ast.Rhs)
+}
+
+// Compile builds a minimized DFA directly from a regular expression
+// pattern. It rejects patterns containing '^' or '$' with a
+// *token.SyntaxError; anchors are only supported by nfa.NFA.Accept and
+// FindAllIndex.
+func Compile(pattern string) (*DFA, error) {
+	tokens, err := token.Tokenize(pattern)
+	if err != nil {
+		return nil, err
+	}
+	ast, err := parser.Parse(tokens)
+	if err != nil {
+		return nil, err
+	}
+	if containsAnchor(ast) {
+		return nil, &token.SyntaxError{Msg: "dfa.Compile does not support ^/$ anchors"}
+	}
+	n := nfa.CreateNFA(ast)
+	return Minimize(FromNFA(n)), nil
+}
+
+// Match reports whether the DFA accepts str in its entirety.
+func (d *DFA) Match(str string) bool {
+	cur := d.StartState
+	for _, c := range str {
+		next, ok := cur.Nexts[c]
+		if !ok {
+			return false
+		}
+		cur = next
+	}
+	return cur.Accept
+}
+
+// FindIndex returns the [start, end) rune offsets of the
+// leftmost-longest match of the DFA in str, or nil if there is no
+// match.
+func (d *DFA) FindIndex(str string) []int {
+	runes := []rune(str)
+	for start := 0; start <= len(runes); start++ {
+		cur := d.StartState
+		lastAccept := -1
+		if cur.Accept {
+			lastAccept = start
+		}
+
+		pos := start
+		for pos < len(runes) {
+			next, ok := cur.Nexts[runes[pos]]
+			if !ok {
+				break
+			}
+			cur = next
+			pos++
+			if cur.Accept {
+				lastAccept = pos
+			}
+		}
+
+		if lastAccept != -1 {
+			return []int{start, lastAccept}
+		}
+	}
+	return nil
+}
+
+// DumpDOT outputs a DOT representation of the DFA, for parity with
+// nfa.NFA.DumpDOT.
+func (d *DFA) DumpDOT() {
+	fmt.Printf("digraph G {\n")
+	fmt.Printf("    q%d [shape = box];\n", d.StartState.ID)
+	for _, s := range d.States {
+		if s.Accept {
+			fmt.Printf("    q%d [shape = doublecircle];\n", s.ID)
+		}
+	}
+
+	for _, src := range d.States {
+		for symbol, dst := range src.Nexts {
+			fmt.Printf("    q%d -> q%d [label=%c];\n", src.ID, dst.ID, symbol)
+		}
+	}
+	fmt.Print("}\n")
+}
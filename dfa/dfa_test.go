@@ -0,0 +1,50 @@
+package dfa
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		input   string
+		want    bool
+	}{
+		{"ab?c", "abc", true},
+		{"ab?c", "ac", true},
+		{"ab?c", "abbc", false},
+		{"a+b", "aaab", true},
+		{"a+b", "b", false},
+		{"(a|b)*c", "aababc", true},
+		{"(a|b)*c", "aababd", false},
+	}
+
+	for _, tt := range tests {
+		d, err := Compile(tt.pattern)
+		if err != nil {
+			t.Fatalf("Compile(%q) = %v", tt.pattern, err)
+		}
+		if got := d.Match(tt.input); got != tt.want {
+			t.Errorf("Match(%q) against %q = %v, want %v", tt.input, tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func TestCompileRejectsAnchors(t *testing.T) {
+	for _, pattern := range []string{"^ab$", "^ab", "ab$"} {
+		if _, err := Compile(pattern); err == nil {
+			t.Errorf("Compile(%q) = nil error, want a SyntaxError rejecting the anchor", pattern)
+		}
+	}
+}
+
+func TestFindIndex(t *testing.T) {
+	d, err := Compile("a+")
+	if err != nil {
+		t.Fatalf("Compile(%q) = %v", "a+", err)
+	}
+
+	got := d.FindIndex("baaab")
+	want := []int{1, 4}
+	if len(got) != 2 || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("FindIndex(%q) = %v, want %v", "baaab", got, want)
+	}
+}
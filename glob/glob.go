@@ -0,0 +1,213 @@
+// Package glob compiles shell/IRC-style glob patterns -- '*', '?',
+// '[abc]'/'[a-z]'/'[^abc]' character classes, and escaped literals --
+// into an *nfa.NFA by translating the glob straight into this
+// module's regex AST, so matching runs through the same NFA simulator
+// as regexp.Regexp.
+package glob
+
+import (
+	"strings"
+
+	"regexp/nfa"
+	"regexp/parser"
+	"regexp/token"
+)
+
+// Matcher matches strings against a compiled glob pattern.
+type Matcher struct {
+	nfa *nfa.NFA
+}
+
+// Compile compiles pattern into a Matcher. '*' matches any run of
+// ASCII runes (including none), '?' matches exactly one ASCII rune,
+// and '[abc]'/'[a-z]'/'[^abc]' are character classes (a negated class
+// also matches only ASCII runes); a non-ASCII rune in the input is
+// matched only by that same rune written literally. Any other rune,
+// including an escaped metacharacter ('\*', '\?', '\[', '\\'),
+// matches itself. If caseFold is true, every literal rune and class
+// member matches either case.
+func Compile(pattern string, caseFold bool) (*Matcher, error) {
+	ast, err := parseGlob(pattern, caseFold)
+	if err != nil {
+		return nil, err
+	}
+	return &Matcher{nfa: nfa.CreateNFA(ast)}, nil
+}
+
+// MatchString reports whether s is matched in its entirety by m.
+func (m *Matcher) MatchString(s string) bool {
+	return m.nfa.Accept(s)
+}
+
+// CompileMask compiles an IRC "nick!user@host" mask, such as
+// "nick*!*@*.example.com", into a Matcher. It splits mask on its
+// first '!' and first '@', compiles the nick, user and host parts as
+// independent globs, and joins them by concatenation around literal
+// '!' and '@' symbols, giving the same matching semantics as
+// ircmatch against the same mask.
+func CompileMask(mask string, caseFold bool) (*Matcher, error) {
+	bang := strings.IndexByte(mask, '!')
+	at := strings.IndexByte(mask, '@')
+	if bang == -1 || at == -1 || at < bang {
+		return nil, &token.SyntaxError{Msg: "mask must have the form nick!user@host"}
+	}
+
+	nickAST, err := parseGlob(mask[:bang], caseFold)
+	if err != nil {
+		return nil, err
+	}
+	userAST, err := parseGlob(mask[bang+1:at], caseFold)
+	if err != nil {
+		return nil, err
+	}
+	hostAST, err := parseGlob(mask[at+1:], caseFold)
+	if err != nil {
+		return nil, err
+	}
+
+	ast := concatAll([]*parser.Node{
+		nickAST,
+		{Type: parser.ND_SYMBOL, Value: '!'},
+		userAST,
+		{Type: parser.ND_SYMBOL, Value: '@'},
+		hostAST,
+	})
+	return &Matcher{nfa: nfa.CreateNFA(ast)}, nil
+}
+
+// emptyNode returns an AST node that compiles to the empty-string
+// match, by way of ND_REPEAT{0,0} (see Generator.genRepeatNFA), since
+// the regex grammar has no atom of its own for "nothing".
+func emptyNode() *parser.Node {
+	return &parser.Node{Type: parser.ND_REPEAT, Min: 0, Max: 0}
+}
+
+// concatAll chains nodes into a single ND_CONCAT tree, left to right.
+func concatAll(nodes []*parser.Node) *parser.Node {
+	if len(nodes) == 0 {
+		return emptyNode()
+	}
+	result := nodes[0]
+	for _, n := range nodes[1:] {
+		result = &parser.Node{Type: parser.ND_CONCAT, Lhs: result, Rhs: n}
+	}
+	return result
+}
+
+// foldCase returns the lowercase and uppercase forms of r; both equal
+// r itself if r is not an ASCII letter.
+func foldCase(r rune) (lo, up rune) {
+	switch {
+	case 'a' <= r && r <= 'z':
+		return r, r - ('a' - 'A')
+	case 'A' <= r && r <= 'Z':
+		return r + ('a' - 'A'), r
+	default:
+		return r, r
+	}
+}
+
+// literalNode builds the AST for a single literal rune, expanding it
+// to a two-member character class when caseFold makes it match more
+// than itself.
+func literalNode(r rune, caseFold bool) *parser.Node {
+	if caseFold {
+		if lo, up := foldCase(r); lo != up {
+			return &parser.Node{Type: parser.ND_CHARCLASS, Runes: []rune{lo, up}}
+		}
+	}
+	return &parser.Node{Type: parser.ND_SYMBOL, Value: r}
+}
+
+// parseGlobClass parses the `[...]` starting at runes[start] == '[',
+// up to and including the closing ']'. Ranges are expanded and a '-'
+// right before ']' is literal, as in parser.charClass, but a reversed
+// range such as 'z-a' adds no members instead of being an error. When
+// caseFold is set, both cases of every member are added.
+func parseGlobClass(runes []rune, start int, caseFold bool) (*parser.Node, int, error) {
+	i := start + 1
+	negated := false
+	if i < len(runes) && runes[i] == '^' {
+		negated = true
+		i++
+	}
+
+	members := []rune{}
+	addMember := func(r rune) {
+		if caseFold {
+			lo, up := foldCase(r)
+			members = append(members, lo, up)
+			return
+		}
+		members = append(members, r)
+	}
+
+	for {
+		if i >= len(runes) {
+			return nil, 0, &token.SyntaxError{Pos: start, Msg: "unterminated character class"}
+		}
+		if runes[i] == ']' {
+			break
+		}
+
+		c := runes[i]
+		if c == '\\' {
+			if i+1 >= len(runes) {
+				return nil, 0, &token.SyntaxError{Pos: i, Msg: "trailing backslash"}
+			}
+			addMember(runes[i+1])
+			i += 2
+			continue
+		}
+		i++
+
+		if i+1 < len(runes) && runes[i] == '-' && runes[i+1] != ']' {
+			hi := runes[i+1]
+			i += 2
+			for r := c; r <= hi; r++ {
+				addMember(r)
+			}
+		} else {
+			addMember(c)
+		}
+	}
+	i++ // consume ']'
+
+	return &parser.Node{Type: parser.ND_CHARCLASS, Runes: members, Negated: negated}, i, nil
+}
+
+// parseGlob turns a glob pattern into the regex AST that matches the
+// same strings.
+func parseGlob(pattern string, caseFold bool) (*parser.Node, error) {
+	runes := []rune(pattern)
+	nodes := []*parser.Node{}
+
+	for i := 0; i < len(runes); {
+		switch c := runes[i]; {
+		case c == '*':
+			nodes = append(nodes, &parser.Node{Type: parser.ND_STAR, Lhs: &parser.Node{Type: parser.ND_ANY}})
+			i++
+		case c == '?':
+			nodes = append(nodes, &parser.Node{Type: parser.ND_ANY})
+			i++
+		case c == '[':
+			node, next, err := parseGlobClass(runes, i, caseFold)
+			if err != nil {
+				return nil, err
+			}
+			nodes = append(nodes, node)
+			i = next
+		case c == '\\':
+			if i+1 >= len(runes) {
+				return nil, &token.SyntaxError{Pos: i, Msg: "trailing backslash"}
+			}
+			nodes = append(nodes, literalNode(runes[i+1], caseFold))
+			i += 2
+		default:
+			nodes = append(nodes, literalNode(c, caseFold))
+			i++
+		}
+	}
+
+	return concatAll(nodes), nil
+}
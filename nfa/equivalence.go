@@ -0,0 +1,185 @@
+package nfa
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// epsilonClosure returns every state reachable from states via zero
+// or more ε-transitions. adaptEpsilonTransition in nfa.go is this same
+// closure, used by the Accept/FindAllIndex simulators; determinize
+// below needs it for subset construction.
+func epsilonClosure(states []*State) []*State {
+	visited := map[int]*State{}
+	stack := append([]*State{}, states...)
+
+	for len(stack) > 0 {
+		s := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if _, ok := visited[s.ID]; ok {
+			continue
+		}
+		visited[s.ID] = s
+		stack = append(stack, s.Nexts['ε']...)
+	}
+
+	out := make([]*State, 0, len(visited))
+	for _, s := range visited {
+		out = append(out, s)
+	}
+	return out
+}
+
+// statesKey returns a canonical string for a set of states, used to
+// dedup subset-construction states during determinize.
+func statesKey(states []*State) string {
+	ids := make([]int, len(states))
+	for i, s := range states {
+		ids[i] = s.ID
+	}
+	sort.Ints(ids)
+
+	strs := make([]string, len(ids))
+	for i, id := range ids {
+		strs[i] = strconv.Itoa(id)
+	}
+	return strings.Join(strs, ",")
+}
+
+// alphabetOf returns every non-ε rune appearing on a transition of any
+// of ns, sorted, for use as the combined alphabet two NFAs are
+// compared over.
+func alphabetOf(ns ...*NFA) []rune {
+	set := map[rune]bool{}
+	for _, n := range ns {
+		for _, s := range n.States {
+			for c := range s.Nexts {
+				if c == 'ε' {
+					continue
+				}
+				set[c] = true
+			}
+		}
+	}
+
+	runes := make([]rune, 0, len(set))
+	for c := range set {
+		runes = append(runes, c)
+	}
+	sort.Slice(runes, func(i, j int) bool { return runes[i] < runes[j] })
+	return runes
+}
+
+// detState is a node of a completed DFA built from an NFA by subset
+// construction over a caller-supplied alphabet: every rune of that
+// alphabet has a transition, falling back to a synthetic dead state
+// when the NFA itself has no edge for it. It exists only to support
+// Equivalent/Contains, so unlike dfa.State it never leaves this file.
+type detState struct {
+	accept bool
+	nexts  map[rune]*detState
+}
+
+// determinize builds a totalized DFA from n over alpha, the combined
+// alphabet of the NFAs being compared, completing missing transitions
+// with a dead (non-accepting, self-looping) state so product
+// construction never has to special-case "no edge".
+func determinize(n *NFA, alpha []rune) *detState {
+	dead := &detState{nexts: make(map[rune]*detState, len(alpha))}
+	for _, c := range alpha {
+		dead.nexts[c] = dead
+	}
+
+	states := map[string]*detState{}
+
+	var build func(set []*State) *detState
+	build = func(set []*State) *detState {
+		set = epsilonClosure(set)
+		k := statesKey(set)
+		if d, ok := states[k]; ok {
+			return d
+		}
+
+		d := &detState{accept: isAcceptSet(set, n.AcceptStates), nexts: map[rune]*detState{}}
+		states[k] = d
+
+		for _, c := range alpha {
+			next := []*State{}
+			for _, s := range set {
+				next = append(next, s.Nexts[c]...)
+			}
+			if len(next) == 0 {
+				d.nexts[c] = dead
+				continue
+			}
+			d.nexts[c] = build(next)
+		}
+
+		return d
+	}
+
+	return build([]*State{n.StartState})
+}
+
+func isAcceptSet(states, accepts []*State) bool {
+	for _, s := range states {
+		if contain(s, accepts) {
+			return true
+		}
+	}
+	return false
+}
+
+// reachesViolation builds the product of a's and b's totalized DFAs
+// over their combined alphabet and walks it breadth-first from the
+// pair of start states, keyed by (stateA, stateB), reporting whether
+// any reachable pair satisfies violates.
+func reachesViolation(a, b *NFA, violates func(acceptA, acceptB bool) bool) bool {
+	alpha := alphabetOf(a, b)
+	startA := determinize(a, alpha)
+	startB := determinize(b, alpha)
+
+	type pair struct{ a, b *detState }
+	start := pair{startA, startB}
+	visited := map[[2]*detState]bool{{start.a, start.b}: true}
+	queue := []pair{start}
+
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+
+		if violates(p.a.accept, p.b.accept) {
+			return true
+		}
+
+		for _, c := range alpha {
+			next := pair{p.a.nexts[c], p.b.nexts[c]}
+			k := [2]*detState{next.a, next.b}
+			if visited[k] {
+				continue
+			}
+			visited[k] = true
+			queue = append(queue, next)
+		}
+	}
+
+	return false
+}
+
+// Equivalent reports whether a and b accept exactly the same
+// language, by determinizing both and checking that no reachable pair
+// of product states disagrees on acceptance.
+func Equivalent(a, b *NFA) bool {
+	return !reachesViolation(a, b, func(acceptA, acceptB bool) bool {
+		return acceptA != acceptB
+	})
+}
+
+// Contains reports whether L(a) is a superset of L(b), i.e. every
+// string b accepts is also accepted by a.
+func Contains(a, b *NFA) bool {
+	return !reachesViolation(a, b, func(acceptA, acceptB bool) bool {
+		return acceptB && !acceptA
+	})
+}
@@ -0,0 +1,43 @@
+package nfa
+
+import "testing"
+
+func TestEquivalent(t *testing.T) {
+	tests := []struct {
+		lhs, rhs string
+		want     bool
+	}{
+		{"a+", "aa*", true},
+		{"(a|b)*", "(b|a)*", true},
+		{"ab|ac", "a(b|c)", true},
+		{"a+", "a*", false},
+	}
+
+	for _, tt := range tests {
+		a := buildNFA(t, tt.lhs)
+		b := buildNFA(t, tt.rhs)
+		if got := Equivalent(a, b); got != tt.want {
+			t.Errorf("Equivalent(%q, %q) = %v, want %v", tt.lhs, tt.rhs, got, tt.want)
+		}
+	}
+}
+
+func TestContains(t *testing.T) {
+	tests := []struct {
+		lhs, rhs string
+		want     bool
+	}{
+		{"a*", "a+", true},
+		{"a+", "a*", false},
+		{"(a|b)*", "ab", true},
+		{"a", "b", false},
+	}
+
+	for _, tt := range tests {
+		a := buildNFA(t, tt.lhs)
+		b := buildNFA(t, tt.rhs)
+		if got := Contains(a, b); got != tt.want {
+			t.Errorf("Contains(%q, %q) = %v, want %v", tt.lhs, tt.rhs, got, tt.want)
+		}
+	}
+}
@@ -1,13 +1,33 @@
 package nfa
 
 import (
+	"bufio"
 	"fmt"
+	"io"
 	"regexp/parser"
 )
 
+// startOfText and endOfText are sentinel "runes" that never occur in
+// real input. ND_BEGIN/ND_END compile to an ordinary symbol edge on
+// one of these sentinels, and the simulators below offer that edge
+// only when the position actually is the start/end of the text,
+// exactly as if it were a conditionally-available ε-transition.
+const (
+	startOfText rune = -1
+	endOfText   rune = -2
+)
+
 type State struct {
 	ID    int
 	Nexts map[rune][]*State
+
+	// GroupEnter/GroupExit mark this state as the "enter group i" /
+	// "exit group i" boundary inserted by genGroupNFA, or -1 if this
+	// state is not a group boundary. A state is reached only via a
+	// plain ε-transition, so FindSubmatch records the capture while
+	// walking the ordinary ε-closure.
+	GroupEnter int
+	GroupExit  int
 }
 
 // NFA is non-deterministic finite automaton
@@ -49,15 +69,40 @@ func contain(state *State, states []*State) bool {
 	return false
 }
 
+// adaptEpsilonTransition returns the transitive ε-closure of states
+// (see epsilonClosure in equivalence.go): every state reachable from
+// them via zero or more ε-transitions. A single ε-hop is not enough
+// because constructs like genQuestionNFA and genGroupNFA chain several
+// ε-states back to back.
 func adaptEpsilonTransition(states []*State) []*State {
+	return epsilonClosure(states)
+}
+
+// adaptBeginTransition follows any startOfText sentinel edges out of
+// states, then re-expands ε. Only call this at an actual start of
+// text (position 0, or after a newline in multiline mode).
+func adaptBeginTransition(states []*State) []*State {
+	nextStates := []*State{}
+	for _, state := range states {
+		if nexts, ok := state.Nexts[startOfText]; ok {
+			nextStates = append(nextStates, nexts...)
+		}
+		nextStates = append(nextStates, state)
+	}
+	return adaptEpsilonTransition(removeDuplicate(nextStates))
+}
+
+// adaptEndTransition follows any endOfText sentinel edges out of
+// states, then re-expands ε. Only call this at the true end of text.
+func adaptEndTransition(states []*State) []*State {
 	nextStates := []*State{}
 	for _, state := range states {
-		if nexts, ok := state.Nexts['ε']; ok {
+		if nexts, ok := state.Nexts[endOfText]; ok {
 			nextStates = append(nextStates, nexts...)
 		}
 		nextStates = append(nextStates, state)
 	}
-	return removeDuplicate(nextStates)
+	return adaptEpsilonTransition(removeDuplicate(nextStates))
 }
 
 func containStateOfEpsilonTransitive(states []*State) bool {
@@ -82,9 +127,11 @@ func (nfa *NFA) isInAcceptState(states []*State) bool {
 func (nfa *NFA) Accept(str string) bool {
 	curStates := []*State{nfa.StartState}
 	curStates = adaptEpsilonTransition(curStates)
+	curStates = adaptBeginTransition(curStates) // str's position 0 is always a start of text
 
 	// the case of empty string
 	if len(str) == 0 {
+		curStates = adaptEndTransition(curStates)
 		return nfa.isInAcceptState(curStates)
 	}
 
@@ -102,9 +149,248 @@ func (nfa *NFA) Accept(str string) bool {
 		// adapt ε transition after each symbol is read.
 		curStates = adaptEpsilonTransition(curStates)
 	}
+	curStates = adaptEndTransition(curStates) // we've just read str's last rune
 	return nfa.isInAcceptState(curStates)
 }
 
+// matchAttempt is one in-flight match, started at rune offset start.
+type matchAttempt struct {
+	start      int
+	states     []*State
+	lastAccept int // rune offset of the last position this attempt was accepting, or -1
+}
+
+// advanceOn follows the symbol-edges for c out of states, then
+// re-expands ε.
+func advanceOn(states []*State, c rune) []*State {
+	nextStates := []*State{}
+	for _, state := range states {
+		if next, ok := state.Nexts[c]; ok {
+			nextStates = append(nextStates, next...)
+		}
+	}
+	return adaptEpsilonTransition(removeDuplicate(nextStates))
+}
+
+// FindAllIndex scans r one rune at a time, maintaining one matchAttempt
+// per rune offset still worth trying, so it never needs to buffer more
+// than the input between two matches. It reports non-overlapping
+// [start, end) rune-offset matches in the order they are found, so
+// callers can grep arbitrarily large streams without loading them into
+// memory. '^' matches at offset 0 or right after a '\n' (multiline);
+// '$' only matches at the true end of the stream.
+func (nfa *NFA) FindAllIndex(r io.Reader) [][2]int {
+	br := bufio.NewReader(r)
+	matches := [][2]int{}
+	attempts := []*matchAttempt{}
+	committed := 0
+	pos := 0
+	prev := rune(0)
+	hasPrev := false
+
+	commit := func(a *matchAttempt) {
+		if a.lastAccept < 0 || a.start < committed {
+			return
+		}
+		matches = append(matches, [2]int{a.start, a.lastAccept})
+		committed = a.lastAccept
+	}
+
+	for {
+		if pos >= committed {
+			atLineStart := pos == 0 || (hasPrev && prev == '\n')
+			states := adaptEpsilonTransition([]*State{nfa.StartState})
+			if atLineStart {
+				states = adaptBeginTransition(states)
+			}
+			lastAccept := -1
+			if nfa.isInAcceptState(states) {
+				lastAccept = pos
+			}
+			attempts = append(attempts, &matchAttempt{start: pos, states: states, lastAccept: lastAccept})
+		}
+
+		c, _, err := br.ReadRune()
+		if err != nil {
+			for _, a := range attempts {
+				if nfa.isInAcceptState(adaptEndTransition(a.states)) {
+					a.lastAccept = pos
+				}
+				commit(a)
+			}
+			break
+		}
+
+		next := attempts[:0]
+		for _, a := range attempts {
+			states := advanceOn(a.states, c)
+			if len(states) == 0 {
+				commit(a)
+				continue
+			}
+			a.states = states
+			if nfa.isInAcceptState(states) {
+				a.lastAccept = pos + 1
+			}
+			next = append(next, a)
+		}
+		attempts = next
+
+		pos++
+		prev = c
+		hasPrev = true
+	}
+
+	return matches
+}
+
+// capSet holds one [start, end) rune-offset pair per capture group;
+// an uncaptured group is [-1, -1].
+type capSet [][2]int
+
+func newCapSet(n int) capSet {
+	caps := make(capSet, n)
+	for i := range caps {
+		caps[i] = [2]int{-1, -1}
+	}
+	return caps
+}
+
+func (c capSet) clone() capSet {
+	out := make(capSet, len(c))
+	copy(out, c)
+	return out
+}
+
+// thread is one live path through the NFA during FindSubmatch: the
+// state it currently sits in, plus the captures accumulated getting
+// there.
+type thread struct {
+	state *State
+	caps  capSet
+}
+
+// numGroups returns 1 + the highest group index appearing in the NFA,
+// or 0 if it has no groups.
+func (nfa *NFA) numGroups() int {
+	max := -1
+	for _, s := range nfa.States {
+		if s.GroupEnter > max {
+			max = s.GroupEnter
+		}
+		if s.GroupExit > max {
+			max = s.GroupExit
+		}
+	}
+	return max + 1
+}
+
+// closure expands threads along ε-transitions, recording group
+// boundaries crossed at the current rune offset pos. Threads are
+// processed in priority order (left union branch before right) and a
+// state already reached by a higher-priority thread is not revisited,
+// which is how ties between competing paths are broken below.
+func closure(threads []thread, pos int) []thread {
+	type frame struct {
+		state *State
+		caps  capSet
+	}
+
+	visited := map[int]bool{}
+	result := []thread{}
+
+	stack := make([]frame, 0, len(threads))
+	for i := len(threads) - 1; i >= 0; i-- {
+		stack = append(stack, frame{threads[i].state, threads[i].caps})
+	}
+
+	for len(stack) > 0 {
+		f := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		if visited[f.state.ID] {
+			continue
+		}
+		visited[f.state.ID] = true
+
+		caps := f.caps
+		if f.state.GroupEnter >= 0 {
+			caps = caps.clone()
+			caps[f.state.GroupEnter][0] = pos
+		}
+		if f.state.GroupExit >= 0 {
+			caps = caps.clone()
+			caps[f.state.GroupExit][1] = pos
+		}
+		result = append(result, thread{f.state, caps})
+
+		nexts := f.state.Nexts['ε']
+		for i := len(nexts) - 1; i >= 0; i-- {
+			if !visited[nexts[i].ID] {
+				stack = append(stack, frame{nexts[i], caps})
+			}
+		}
+	}
+
+	return result
+}
+
+// firstAccepting returns the highest-priority thread sitting in an
+// accept state, or nil.
+func firstAccepting(threads []thread, accepts []*State) *thread {
+	for i := range threads {
+		if contain(threads[i].state, accepts) {
+			return &threads[i]
+		}
+	}
+	return nil
+}
+
+// FindSubmatch matches str against nfa in its entirety and, on
+// success, returns the whole match followed by the text captured by
+// each group in declaration order (nfa.FindSubmatch(s)[0] is always
+// s itself; an unmatched group is ""). It returns nil if str is not
+// accepted. When more than one path can reach the same state, the
+// leftmost union branch wins, matching Thompson-construction priority
+// rather than POSIX leftmost-longest.
+func (nfa *NFA) FindSubmatch(str string) []string {
+	numGroups := nfa.numGroups()
+	runes := []rune(str)
+
+	threads := closure([]thread{{nfa.StartState, newCapSet(numGroups)}}, 0)
+
+	for i, c := range runes {
+		next := []thread{}
+		seen := map[int]bool{}
+		for _, t := range threads {
+			for _, dst := range t.state.Nexts[c] {
+				if seen[dst.ID] {
+					continue
+				}
+				seen[dst.ID] = true
+				next = append(next, thread{dst, t.caps})
+			}
+		}
+
+		threads = closure(next, i+1)
+	}
+
+	best := firstAccepting(threads, nfa.AcceptStates)
+	if best == nil {
+		return nil
+	}
+
+	result := make([]string, numGroups+1)
+	result[0] = str
+	for i, cap := range best.caps {
+		if cap[0] < 0 || cap[1] < 0 {
+			continue
+		}
+		result[i+1] = string(runes[cap[0]:cap[1]])
+	}
+	return result
+}
+
 // DumpDOT outputs a DOT. DOT is a graph description language.
 // The start state forms square box and the accept states form double circle.
 func (nfa *NFA) DumpDOT() {
@@ -139,8 +425,10 @@ func (g *Generator) newState() *State {
 	id := g.StateCount
 	g.StateCount++
 	return &State{
-		ID:    id,
-		Nexts: make(map[rune][]*State),
+		ID:         id,
+		Nexts:      make(map[rune][]*State),
+		GroupEnter: -1,
+		GroupExit:  -1,
 	}
 }
 
@@ -186,6 +474,85 @@ func (g *Generator) genConcateNFA(lhs, rhs *NFA) *NFA {
 	return newNFA(states, start, accepts)
 }
 
+// asciiRunes returns every rune in the ASCII range, used as the
+// finite alphabet that negated character classes and '.' are
+// completed against.
+func asciiRunes() []rune {
+	runes := make([]rune, 0, 128)
+	for c := rune(0); c < 128; c++ {
+		runes = append(runes, c)
+	}
+	return runes
+}
+
+func (g *Generator) genCharClassNFA(node *parser.Node) *NFA {
+	src := g.newState()
+	dst := g.newState()
+
+	members := node.Runes
+	if node.Negated {
+		excluded := make(map[rune]bool, len(node.Runes))
+		for _, r := range node.Runes {
+			excluded[r] = true
+		}
+		members = nil
+		for _, r := range asciiRunes() {
+			if !excluded[r] {
+				members = append(members, r)
+			}
+		}
+	}
+
+	for _, r := range members {
+		src.Nexts[r] = append(src.Nexts[r], dst)
+	}
+
+	states := []*State{src, dst}
+	accepts := []*State{dst}
+	return newNFA(states, src, accepts)
+}
+
+func (g *Generator) genAnyNFA() *NFA {
+	return g.genCharClassNFA(&parser.Node{Negated: true})
+}
+
+// genEmptyNFA builds an NFA matching only the empty string: a single
+// state that is both start and accept.
+func (g *Generator) genEmptyNFA() *NFA {
+	s := g.newState()
+	return newNFA([]*State{s}, s, []*State{s})
+}
+
+func (g *Generator) genQuestionNFA(old *NFA) *NFA {
+	return g.genUnionNFA(old, g.genEmptyNFA())
+}
+
+func (g *Generator) genPlusNFA(first, second *NFA) *NFA {
+	return g.genConcateNFA(first, g.genStarNFA(second))
+}
+
+// genGroupNFA wraps child between an "enter group idx" and an "exit
+// group idx" marker state, connected by plain ε-transitions so Accept
+// still treats them like any other ε-edge; only FindSubmatch looks at
+// GroupEnter/GroupExit.
+func (g *Generator) genGroupNFA(idx int, child *NFA) *NFA {
+	enter := g.newState()
+	enter.GroupEnter = idx
+	enter.Nexts['ε'] = []*State{child.StartState}
+
+	exit := g.newState()
+	exit.GroupExit = idx
+	for _, acc := range child.AcceptStates {
+		tmp := acc.Nexts['ε']
+		tmp = append(tmp, exit)
+		acc.Nexts['ε'] = tmp
+	}
+
+	states := append([]*State{enter}, child.States...)
+	states = append(states, exit)
+	return newNFA(states, enter, []*State{exit})
+}
+
 func (g *Generator) genStarNFA(old *NFA) *NFA {
 	start := g.newState()
 	start.Nexts['ε'] = []*State{old.StartState}
@@ -205,6 +572,41 @@ func (g *Generator) genStarNFA(old *NFA) *NFA {
 	return newNFA(states, start, accepts)
 }
 
+// genRepeatNFA builds `childAST{min,max}` by concatenating min
+// independent copies of childAST (each re-generated from the AST so
+// their states don't alias), then either:
+//   - one more copy wrapped in genStarNFA, if max is unbounded (-1), or
+//   - max-min more copies, each wrapped in genQuestionNFA, if max is
+//     bounded.
+func (g *Generator) genRepeatNFA(childAST *parser.Node, min, max int) *NFA {
+	if min == 0 && max == 0 {
+		return g.genEmptyNFA()
+	}
+
+	var result *NFA
+	concat := func(next *NFA) {
+		if result == nil {
+			result = next
+		} else {
+			result = g.genConcateNFA(result, next)
+		}
+	}
+
+	for i := 0; i < min; i++ {
+		concat(g.gen(childAST))
+	}
+
+	if max == -1 {
+		concat(g.genStarNFA(g.gen(childAST)))
+	} else {
+		for i := min; i < max; i++ {
+			concat(g.genQuestionNFA(g.gen(childAST)))
+		}
+	}
+
+	return result
+}
+
 func (g *Generator) gen(node *parser.Node) *NFA {
 	switch node.Type {
 	case parser.ND_SYMBOL:
@@ -220,6 +622,28 @@ func (g *Generator) gen(node *parser.Node) *NFA {
 	case parser.ND_STAR:
 		old := g.gen(node.Lhs)
 		return g.genStarNFA(old)
+	case parser.ND_QUESTION:
+		old := g.gen(node.Lhs)
+		return g.genQuestionNFA(old)
+	case parser.ND_PLUS:
+		// Two independent copies of the subtree so the "one" and the
+		// "zero-or-more" halves don't alias the same states.
+		first := g.gen(node.Lhs)
+		second := g.gen(node.Lhs)
+		return g.genPlusNFA(first, second)
+	case parser.ND_ANY:
+		return g.genAnyNFA()
+	case parser.ND_CHARCLASS:
+		return g.genCharClassNFA(node)
+	case parser.ND_GROUP:
+		child := g.gen(node.Lhs)
+		return g.genGroupNFA(node.Index, child)
+	case parser.ND_BEGIN:
+		return g.genSymbolNFA(startOfText)
+	case parser.ND_END:
+		return g.genSymbolNFA(endOfText)
+	case parser.ND_REPEAT:
+		return g.genRepeatNFA(node.Lhs, node.Min, node.Max)
 	}
 	return nil
 }
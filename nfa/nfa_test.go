@@ -0,0 +1,106 @@
+package nfa
+
+import (
+	"reflect"
+	"regexp/parser"
+	"regexp/token"
+	"strings"
+	"testing"
+)
+
+// buildNFA compiles pattern straight down to an NFA, the same path
+// regexp.Compile and dfa.Compile use, so tests exercise the real
+// tokenizer/parser/generator pipeline rather than hand-built graphs.
+func buildNFA(t *testing.T, pattern string) *NFA {
+	t.Helper()
+
+	tokens, err := token.Tokenize(pattern)
+	if err != nil {
+		t.Fatalf("Tokenize(%q) = %v", pattern, err)
+	}
+	ast, err := parser.Parse(tokens)
+	if err != nil {
+		t.Fatalf("Parse(%q) = %v", pattern, err)
+	}
+	return CreateNFA(ast)
+}
+
+func TestAcceptQuestion(t *testing.T) {
+	tests := []struct {
+		pattern string
+		input   string
+		want    bool
+	}{
+		{"ab?c", "abc", true},
+		{"ab?c", "ac", true},
+		{"ab?c", "abbc", false},
+		{"xa?y", "xy", true},
+		{"xa?y", "xay", true},
+	}
+
+	for _, tt := range tests {
+		n := buildNFA(t, tt.pattern)
+		if got := n.Accept(tt.input); got != tt.want {
+			t.Errorf("Accept(%q) against %q = %v, want %v", tt.input, tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func TestFindSubmatch(t *testing.T) {
+	n := buildNFA(t, "(a+)(b+)")
+
+	got := n.FindSubmatch("aaabb")
+	want := []string{"aaabb", "aaa", "bb"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FindSubmatch(%q) = %v, want %v", "aaabb", got, want)
+	}
+
+	if got := n.FindSubmatch("no match"); got != nil {
+		t.Errorf("FindSubmatch(%q) = %v, want nil", "no match", got)
+	}
+}
+
+func TestAcceptGroupRepetition(t *testing.T) {
+	tests := []struct {
+		pattern string
+		input   string
+		want    bool
+	}{
+		{"(ab)+", "ab", true},
+		{"(ab)+", "abab", true},
+		{"(ab)+", "aba", false},
+		{"(a)+", "aa", true},
+		{"(ab)*", "", true},
+		{"(ab)*", "abab", true},
+	}
+
+	for _, tt := range tests {
+		n := buildNFA(t, tt.pattern)
+		if got := n.Accept(tt.input); got != tt.want {
+			t.Errorf("Accept(%q) against %q = %v, want %v", tt.input, tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func TestFindAllIndex(t *testing.T) {
+	tests := []struct {
+		pattern string
+		input   string
+		want    [][2]int
+	}{
+		{"a+", "baaab aa", [][2]int{{1, 4}, {6, 8}}},
+		{"a+b", "aaab", [][2]int{{0, 4}}},
+		{"a+b", "no match here", nil},
+	}
+
+	for _, tt := range tests {
+		n := buildNFA(t, tt.pattern)
+		got := n.FindAllIndex(strings.NewReader(tt.input))
+		if len(got) == 0 {
+			got = nil
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("FindAllIndex(%q) over %q = %v, want %v", tt.pattern, tt.input, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,342 @@
+package parser
+
+import (
+	"fmt"
+	"regexp/token"
+)
+
+type NodeType int
+
+const (
+	ND_SYMBOL NodeType = iota
+	ND_UNION
+	ND_CONCAT
+	ND_STAR
+	ND_QUESTION
+	ND_PLUS
+	ND_ANY
+	ND_CHARCLASS
+	ND_GROUP
+	ND_BEGIN
+	ND_END
+	ND_REPEAT
+)
+
+// Node is a node of the regular expression AST. Runes and Negated are
+// only meaningful for ND_CHARCLASS, which holds its member runes
+// already expanded (ranges and \d/\w/\s shorthands included). Index
+// is only meaningful for ND_GROUP, whose child is held in Lhs. Min
+// and Max are only meaningful for ND_REPEAT, whose child is held in
+// Lhs; Max is -1 for an unbounded `{n,}`.
+type Node struct {
+	Type    NodeType
+	Value   rune
+	Lhs     *Node
+	Rhs     *Node
+	Runes   []rune
+	Negated bool
+	Index   int
+	Min     int
+	Max     int
+}
+
+func newSymbolNode(value rune) *Node {
+	return &Node{Type: ND_SYMBOL, Value: value}
+}
+
+func newUnionNode(lhs, rhs *Node) *Node {
+	return &Node{Type: ND_UNION, Lhs: lhs, Rhs: rhs}
+}
+
+func newConcatNode(lhs, rhs *Node) *Node {
+	return &Node{Type: ND_CONCAT, Lhs: lhs, Rhs: rhs}
+}
+
+func newStarNode(lhs *Node) *Node {
+	return &Node{Type: ND_STAR, Lhs: lhs}
+}
+
+func newQuestionNode(lhs *Node) *Node {
+	return &Node{Type: ND_QUESTION, Lhs: lhs}
+}
+
+func newPlusNode(lhs *Node) *Node {
+	return &Node{Type: ND_PLUS, Lhs: lhs}
+}
+
+func newAnyNode() *Node {
+	return &Node{Type: ND_ANY}
+}
+
+func newCharClassNode(runes []rune, negated bool) *Node {
+	return &Node{Type: ND_CHARCLASS, Runes: runes, Negated: negated}
+}
+
+func newGroupNode(index int, child *Node) *Node {
+	return &Node{Type: ND_GROUP, Index: index, Lhs: child}
+}
+
+func newBeginNode() *Node {
+	return &Node{Type: ND_BEGIN}
+}
+
+func newEndNode() *Node {
+	return &Node{Type: ND_END}
+}
+
+func newRepeatNode(child *Node, min, max int) *Node {
+	return &Node{Type: ND_REPEAT, Lhs: child, Min: min, Max: max}
+}
+
+// rangeRunes returns every rune in [lo, hi], or nil if the range is
+// empty or reversed (callers are expected to reject hi < lo with a
+// *token.SyntaxError before it gets here, but this keeps the helper
+// itself safe to call).
+func rangeRunes(lo, hi rune) []rune {
+	if hi < lo {
+		return nil
+	}
+	runes := make([]rune, 0, hi-lo+1)
+	for c := lo; c <= hi; c++ {
+		runes = append(runes, c)
+	}
+	return runes
+}
+
+// expandEscape returns the runes an escape sequence stands for: the
+// \d/\w/\s shorthand classes, \n/\t whitespace, or the single rune of
+// a literal metacharacter escape (\. \* \| ...).
+func expandEscape(r rune) []rune {
+	switch r {
+	case 'd':
+		return rangeRunes('0', '9')
+	case 'w':
+		runes := rangeRunes('a', 'z')
+		runes = append(runes, rangeRunes('A', 'Z')...)
+		runes = append(runes, rangeRunes('0', '9')...)
+		return append(runes, '_')
+	case 's':
+		return []rune{' ', '\t', '\n', '\r'}
+	case 'n':
+		return []rune{'\n'}
+	case 't':
+		return []rune{'\t'}
+	default:
+		return []rune{r}
+	}
+}
+
+// isShorthandClass reports whether an escape expands to more than its
+// own literal rune, i.e. it denotes a character class rather than a
+// single symbol.
+func isShorthandClass(r rune) bool {
+	return r == 'd' || r == 'w' || r == 's'
+}
+
+// maxRepeat caps the bounds of a `{n}`/`{n,}`/`{n,m}` quantifier, so a
+// pattern like `a{1000000}` can't blow up the NFA it compiles to.
+const maxRepeat = 1000
+
+// parser turns a token stream into an AST using the grammar
+//
+//	union   = concat ('|' concat)*
+//	concat  = postfix ('・' postfix)*
+//	postfix = primary ('*' | '?' | '+' | '{n}' | '{n,}' | '{n,m}')*
+//	primary = SYMBOL | '.' | '[' charclass ']' | '(' union ')' | '^' | '$' | ESCAPE
+type parser struct {
+	tokens     []token.Token
+	pos        int
+	groupCount int
+}
+
+func (p *parser) cur() token.Token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) advance() token.Token {
+	t := p.tokens[p.pos]
+	p.pos++
+	return t
+}
+
+func (p *parser) unexpected(t token.Token) error {
+	return &token.SyntaxError{
+		Pos:  t.Pos,
+		Rune: t.Value,
+		Msg:  fmt.Sprintf("unexpected %q", t.Value),
+	}
+}
+
+func (p *parser) union() (*Node, error) {
+	node, err := p.concat()
+	if err != nil {
+		return nil, err
+	}
+	for p.cur().Type == token.TK_UNION {
+		p.advance()
+		rhs, err := p.concat()
+		if err != nil {
+			return nil, err
+		}
+		node = newUnionNode(node, rhs)
+	}
+	return node, nil
+}
+
+func (p *parser) concat() (*Node, error) {
+	node, err := p.postfix()
+	if err != nil {
+		return nil, err
+	}
+	for p.cur().Type == token.TK_CONCAT {
+		p.advance()
+		rhs, err := p.postfix()
+		if err != nil {
+			return nil, err
+		}
+		node = newConcatNode(node, rhs)
+	}
+	return node, nil
+}
+
+func (p *parser) postfix() (*Node, error) {
+	node, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch p.cur().Type {
+		case token.TK_STAR:
+			p.advance()
+			node = newStarNode(node)
+		case token.TK_QUESTION:
+			p.advance()
+			node = newQuestionNode(node)
+		case token.TK_PLUS:
+			p.advance()
+			node = newPlusNode(node)
+		case token.TK_REPEAT:
+			t := p.cur()
+			if t.Min > maxRepeat || (t.Max != -1 && t.Max > maxRepeat) {
+				return nil, &token.SyntaxError{Pos: t.Pos, Msg: fmt.Sprintf("repetition count exceeds limit of %d", maxRepeat)}
+			}
+			p.advance()
+			node = newRepeatNode(node, t.Min, t.Max)
+		default:
+			return node, nil
+		}
+	}
+}
+
+// primary consumes one atom. It never advances past TK_EOF, so a
+// malformed pattern (e.g. a trailing '|' or '*') surfaces as a
+// *token.SyntaxError instead of running the parser past the end of
+// the token stream.
+func (p *parser) primary() (*Node, error) {
+	t := p.cur()
+	switch t.Type {
+	case token.TK_EOF, token.TK_UNION, token.TK_CONCAT, token.TK_STAR, token.TK_QUESTION, token.TK_PLUS, token.TK_REPEAT, token.TK_RBRACKET, token.TK_RPAREN:
+		return nil, &token.SyntaxError{Pos: t.Pos, Rune: t.Value, Msg: "unexpected end of expression"}
+	}
+
+	p.advance()
+	switch t.Type {
+	case token.TK_DOT:
+		return newAnyNode(), nil
+	case token.TK_LBRACKET:
+		return p.charClass()
+	case token.TK_LPAREN:
+		return p.group()
+	case token.TK_BEGIN:
+		return newBeginNode(), nil
+	case token.TK_END:
+		return newEndNode(), nil
+	case token.TK_ESCAPE:
+		if isShorthandClass(t.Value) {
+			return newCharClassNode(expandEscape(t.Value), false), nil
+		}
+		return newSymbolNode(expandEscape(t.Value)[0]), nil
+	default:
+		return newSymbolNode(t.Value), nil
+	}
+}
+
+// group parses the inside of a `(...)` that was already opened by the
+// TK_LPAREN consumed in primary, up to and including the closing
+// TK_RPAREN. Groups are numbered in the order their '(' appears.
+func (p *parser) group() (*Node, error) {
+	index := p.groupCount
+	p.groupCount++
+
+	child, err := p.union()
+	if err != nil {
+		return nil, err
+	}
+	if p.cur().Type != token.TK_RPAREN {
+		return nil, p.unexpected(p.cur())
+	}
+	p.advance()
+
+	return newGroupNode(index, child), nil
+}
+
+// charClass parses the inside of a `[...]` that was already opened by
+// the TK_LBRACKET consumed in primary, up to and including the
+// closing TK_RBRACKET.
+func (p *parser) charClass() (*Node, error) {
+	negated := false
+	if p.cur().Type == token.TK_SYMBOL && p.cur().Value == '^' {
+		negated = true
+		p.advance()
+	}
+
+	runes := []rune{}
+	for p.cur().Type != token.TK_RBRACKET {
+		if p.cur().Type == token.TK_EOF {
+			return nil, &token.SyntaxError{Pos: p.cur().Pos, Msg: "unterminated character class"}
+		}
+		t := p.advance()
+
+		if t.Type == token.TK_ESCAPE {
+			runes = append(runes, expandEscape(t.Value)...)
+			continue
+		}
+
+		lo := t.Value
+		// A '-' right before the closing ']' (e.g. "[a-]") has
+		// nothing to range to, so it's a literal '-' rather than the
+		// start of a range.
+		isDash := p.cur().Type == token.TK_SYMBOL && p.cur().Value == '-'
+		if isDash && p.tokens[p.pos+1].Type == token.TK_RBRACKET {
+			isDash = false
+		}
+
+		if isDash {
+			dash := p.advance()
+			hiTok := p.advance()
+			hi := hiTok.Value
+			if hi < lo {
+				return nil, &token.SyntaxError{Pos: dash.Pos, Msg: "invalid character class range"}
+			}
+			runes = append(runes, rangeRunes(lo, hi)...)
+		} else {
+			runes = append(runes, lo)
+		}
+	}
+	p.advance() // consume ']'
+
+	return newCharClassNode(runes, negated), nil
+}
+
+// Parse builds an AST from tokens produced by token.Tokenize.
+func Parse(tokens []token.Token) (*Node, error) {
+	p := &parser{tokens: tokens}
+	node, err := p.union()
+	if err != nil {
+		return nil, err
+	}
+	if p.cur().Type != token.TK_EOF {
+		return nil, p.unexpected(p.cur())
+	}
+	return node, nil
+}
@@ -0,0 +1,49 @@
+package parser
+
+import (
+	"regexp/token"
+	"testing"
+)
+
+func parse(t *testing.T, pattern string) (*Node, error) {
+	t.Helper()
+	tokens, err := token.Tokenize(pattern)
+	if err != nil {
+		t.Fatalf("Tokenize(%q) = %v", pattern, err)
+	}
+	return Parse(tokens)
+}
+
+// TestCharClassRangeErrors guards against the panic rangeRunes used to
+// hit on a reversed range: hi < lo made make([]rune, 0, hi-lo+1) ask
+// for a negative capacity.
+func TestCharClassRangeErrors(t *testing.T) {
+	for _, pattern := range []string{"[z-a]", "[z-x]"} {
+		if _, err := parse(t, pattern); err == nil {
+			t.Errorf("Parse(%q) = nil error, want a SyntaxError for the reversed range", pattern)
+		}
+	}
+}
+
+// TestCharClassTrailingDash checks that a '-' immediately before ']'
+// is a literal dash rather than the start of a range with nothing to
+// range to.
+func TestCharClassTrailingDash(t *testing.T) {
+	node, err := parse(t, "[a-]")
+	if err != nil {
+		t.Fatalf("Parse(%q) = %v", "[a-]", err)
+	}
+	if node.Type != ND_CHARCLASS {
+		t.Fatalf("Parse(%q) produced %v, want ND_CHARCLASS", "[a-]", node.Type)
+	}
+
+	want := map[rune]bool{'a': true, '-': true}
+	if len(node.Runes) != len(want) {
+		t.Fatalf("Parse(%q).Runes = %v, want %v", "[a-]", node.Runes, want)
+	}
+	for _, r := range node.Runes {
+		if !want[r] {
+			t.Errorf("Parse(%q).Runes contains unexpected rune %q", "[a-]", r)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+// Package regexp is the top-level entry point of this regular
+// expression engine: Compile turns a pattern into a Regexp backed by
+// the package's NFA simulator.
+package regexp
+
+import (
+	"regexp/nfa"
+	"regexp/parser"
+	"regexp/token"
+)
+
+// Regexp is a compiled regular expression.
+type Regexp struct {
+	nfa *nfa.NFA
+}
+
+// Compile parses pattern and builds a Regexp, or returns a
+// *token.SyntaxError describing where the pattern is malformed.
+func Compile(pattern string) (*Regexp, error) {
+	tokens, err := token.Tokenize(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	ast, err := parser.Parse(tokens)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Regexp{nfa: nfa.CreateNFA(ast)}, nil
+}
+
+// MatchString reports whether s is matched in its entirety by re.
+func (re *Regexp) MatchString(s string) bool {
+	return re.nfa.Accept(s)
+}
@@ -2,63 +2,240 @@ package token
 
 import (
 	"fmt"
-	"os"
+	"strconv"
+	"strings"
 )
 
 type TokenType int
 
 const (
-	TK_SYMBOL TokenType = iota // 'a', 't', 'D',..
-	TK_UNION                   // '|'
-	TK_CONCAT                  // '・' (・ is usually omitted in regular expression)
-	TK_STAR                    // '*'
-	TK_EOF                     // EOF
+	TK_SYMBOL   TokenType = iota // 'a', 't', 'D',..
+	TK_UNION                     // '|'
+	TK_CONCAT                    // '・' (・ is usually omitted in regular expression)
+	TK_STAR                      // '*'
+	TK_QUESTION                  // '?'
+	TK_PLUS                      // '+'
+	TK_DOT                       // '.'
+	TK_LBRACKET                  // '['
+	TK_RBRACKET                  // ']'
+	TK_LPAREN                    // '('
+	TK_RPAREN                    // ')'
+	TK_BEGIN                     // '^'
+	TK_END                       // '$'
+	TK_REPEAT                    // '{3}', '{2,}', '{2,5}'
+	TK_ESCAPE                    // '\n', '\d', '\.', ..
+	TK_EOF                       // EOF
 )
 
+// Token carries Pos, the rune offset in the original pattern it was
+// read from, so later stages can report SyntaxErrors against the
+// source text. Min/Max are only meaningful for TK_REPEAT: Max is -1
+// for an unbounded `{n,}`.
 type Token struct {
 	Type  TokenType
 	Value rune
+	Pos   int
+	Min   int
+	Max   int
 }
 
-func newToken(tt TokenType, value rune) Token {
+func newToken(tt TokenType, value rune, pos int) Token {
 	return Token{
 		Type:  tt,
 		Value: value,
+		Pos:   pos,
 	}
 }
 
+// SyntaxError reports a malformed pattern at a specific rune offset.
+type SyntaxError struct {
+	Pos  int
+	Rune rune
+	Msg  string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("regexp: %s at position %d", e.Msg, e.Pos)
+}
+
+// Pretty renders pattern with a caret under the offending column.
+func (e *SyntaxError) Pretty(pattern string) string {
+	var b strings.Builder
+	b.WriteString(pattern)
+	b.WriteByte('\n')
+	for _, r := range []rune(pattern)[:e.Pos] {
+		if r == '\t' {
+			b.WriteByte('\t')
+		} else {
+			b.WriteByte(' ')
+		}
+	}
+	b.WriteByte('^')
+	return b.String()
+}
+
 func isChar(c rune) bool {
 	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
 }
 
-func lastTokenIsSymbol(tokens []Token) bool {
+// endsAtom reports whether a token of type tt can be the last token of
+// an atom, i.e. whether a following atom-starting token needs an
+// implicit TK_CONCAT inserted before it.
+func endsAtom(tt TokenType) bool {
+	switch tt {
+	case TK_SYMBOL, TK_STAR, TK_QUESTION, TK_PLUS, TK_DOT, TK_RBRACKET, TK_RPAREN, TK_BEGIN, TK_END, TK_REPEAT, TK_ESCAPE:
+		return true
+	}
+	return false
+}
+
+func lastTokenEndsAtom(tokens []Token) bool {
 	if len(tokens) == 0 {
 		return false
 	}
+	return endsAtom(tokens[len(tokens)-1].Type)
+}
+
+func isDigit(c rune) bool {
+	return '0' <= c && c <= '9'
+}
 
-	return tokens[len(tokens)-1].Type == TK_SYMBOL
+func scanDigits(runes []rune, i int) (string, int) {
+	start := i
+	for i < len(runes) && isDigit(runes[i]) {
+		i++
+	}
+	return string(runes[start:i]), i
 }
 
-func Tokenize(regexp string) []Token {
+// scanRepeat parses a `{n}`, `{n,}` or `{n,m}` quantifier starting at
+// runes[start] == '{', returning the TK_REPEAT token and the index of
+// its closing '}'.
+func scanRepeat(runes []rune, start int) (Token, int, error) {
+	i := start + 1
+
+	minStr, i := scanDigits(runes, i)
+	if minStr == "" {
+		return Token{}, 0, &SyntaxError{Pos: start, Msg: "missing repetition minimum"}
+	}
+	min, _ := strconv.Atoi(minStr)
+
+	max := min
+	if i < len(runes) && runes[i] == ',' {
+		i++
+		maxStr, next := scanDigits(runes, i)
+		i = next
+		if maxStr == "" {
+			max = -1
+		} else {
+			max, _ = strconv.Atoi(maxStr)
+		}
+	}
+
+	if i >= len(runes) || runes[i] != '}' {
+		return Token{}, 0, &SyntaxError{Pos: start, Msg: "unterminated repetition"}
+	}
+	if max != -1 && max < min {
+		return Token{}, 0, &SyntaxError{Pos: start, Msg: "repetition max is less than min"}
+	}
+
+	return Token{Type: TK_REPEAT, Pos: start, Min: min, Max: max}, i, nil
+}
+
+// Tokenize turns a pattern into a token stream, or a *SyntaxError if
+// the pattern is malformed. While inside a `[...]` character class,
+// every rune other than `]` (or an escape) is emitted as a plain
+// TK_SYMBOL with no implicit TK_CONCAT, since the parser consumes the
+// whole class as a single unit.
+func Tokenize(regexp string) ([]Token, error) {
 	tokens := []Token{}
-	var t Token
-	for _, c := range regexp {
-		if isChar(c) {
-			if lastTokenIsSymbol(tokens) {
-				t = newToken(TK_CONCAT, '・')
-				tokens = append(tokens, t)
+	inClass := false
+
+	runes := []rune(regexp)
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+
+		if c == '\\' {
+			pos := i
+			if i+1 >= len(runes) {
+				return nil, &SyntaxError{Pos: pos, Rune: c, Msg: "trailing backslash"}
 			}
-			t = newToken(TK_SYMBOL, c)
-		} else if c == '|' {
-			t = newToken(TK_UNION, c)
-		} else if c == '*' {
-			t = newToken(TK_STAR, c)
-		} else {
-			fmt.Printf("unexpected input: %c", c)
-			os.Exit(1)
+			i++
+			if !inClass && lastTokenEndsAtom(tokens) {
+				tokens = append(tokens, newToken(TK_CONCAT, '・', pos))
+			}
+			tokens = append(tokens, newToken(TK_ESCAPE, runes[i], pos))
+			continue
+		}
+
+		if inClass {
+			if c == ']' {
+				tokens = append(tokens, newToken(TK_RBRACKET, c, i))
+				inClass = false
+			} else {
+				tokens = append(tokens, newToken(TK_SYMBOL, c, i))
+			}
+			continue
+		}
+
+		switch {
+		case isChar(c):
+			if lastTokenEndsAtom(tokens) {
+				tokens = append(tokens, newToken(TK_CONCAT, '・', i))
+			}
+			tokens = append(tokens, newToken(TK_SYMBOL, c, i))
+		case c == '|':
+			tokens = append(tokens, newToken(TK_UNION, c, i))
+		case c == '*':
+			tokens = append(tokens, newToken(TK_STAR, c, i))
+		case c == '?':
+			tokens = append(tokens, newToken(TK_QUESTION, c, i))
+		case c == '+':
+			tokens = append(tokens, newToken(TK_PLUS, c, i))
+		case c == '.':
+			if lastTokenEndsAtom(tokens) {
+				tokens = append(tokens, newToken(TK_CONCAT, '・', i))
+			}
+			tokens = append(tokens, newToken(TK_DOT, c, i))
+		case c == '[':
+			if lastTokenEndsAtom(tokens) {
+				tokens = append(tokens, newToken(TK_CONCAT, '・', i))
+			}
+			tokens = append(tokens, newToken(TK_LBRACKET, c, i))
+			inClass = true
+		case c == '(':
+			if lastTokenEndsAtom(tokens) {
+				tokens = append(tokens, newToken(TK_CONCAT, '・', i))
+			}
+			tokens = append(tokens, newToken(TK_LPAREN, c, i))
+		case c == ')':
+			tokens = append(tokens, newToken(TK_RPAREN, c, i))
+		case c == '^':
+			if lastTokenEndsAtom(tokens) {
+				tokens = append(tokens, newToken(TK_CONCAT, '・', i))
+			}
+			tokens = append(tokens, newToken(TK_BEGIN, c, i))
+		case c == '$':
+			if lastTokenEndsAtom(tokens) {
+				tokens = append(tokens, newToken(TK_CONCAT, '・', i))
+			}
+			tokens = append(tokens, newToken(TK_END, c, i))
+		case c == '{':
+			t, newI, err := scanRepeat(runes, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, t)
+			i = newI
+		default:
+			return nil, &SyntaxError{Pos: i, Rune: c, Msg: fmt.Sprintf("unexpected input: %c", c)}
 		}
-		tokens = append(tokens, t)
 	}
-	tokens = append(tokens, newToken(TK_EOF, '\000'))
-	return tokens
+
+	if inClass {
+		return nil, &SyntaxError{Pos: len(runes), Msg: "unterminated character class"}
+	}
+
+	tokens = append(tokens, newToken(TK_EOF, '\000', len(runes)))
+	return tokens, nil
 }